@@ -28,7 +28,11 @@ func (a *AverageSimilarity) Train(input []float32) float32 {
 		sum += math.Abs(Similarity(input, a.vectors[c]))
 		c = (c + 1) % vectorsSize
 	}
-	averageSimilarity := float32(sum / float64(a.length))
+
+	var averageSimilarity float32
+	if a.length > 0 {
+		averageSimilarity = float32(sum / float64(a.length))
+	}
 
 	if a.length < vectorsSize {
 		a.vectors[a.begin+a.length] = input
@@ -40,3 +44,13 @@ func (a *AverageSimilarity) Train(input []float32) float32 {
 
 	return averageSimilarity
 }
+
+// Observe satisfies Scorer: it is Train under the streaming name.
+func (a *AverageSimilarity) Observe(x []float32) float32 {
+	return a.Train(x)
+}
+
+// Reset satisfies Scorer by discarding every vector seen so far.
+func (a *AverageSimilarity) Reset() {
+	a.begin, a.length = 0, 0
+}
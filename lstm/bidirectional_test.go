@@ -0,0 +1,17 @@
+package lstm
+
+import "testing"
+
+// TestEncodeBidirectionalConcatenatesBothDirections exercises the
+// Bidirectional path of Encode, which runs lsBack - tied to ls, see
+// NewBidirectionalLSTMModel - back-to-front over the reversed sentence.
+func TestEncodeBidirectionalConcatenatesBothDirections(t *testing.T) {
+	m := NewBidirectionalLSTMModel(3, 2, 3, []int{2}, nil, 0.1)
+	vocab := testVocabulary("ab")
+	r := NewCharRNN(m, vocab, 1)
+
+	got := r.Encode([]rune("ab"))
+	if len(got) != 4 {
+		t.Fatalf("Encode returned %d floats, want 4 (forward + backward hidden)", len(got))
+	}
+}
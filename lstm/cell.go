@@ -0,0 +1,154 @@
+package lstm
+
+import (
+	. "gorgonia.org/gorgonia"
+)
+
+// CellKind selects the recurrent gate equations a layer uses.
+type CellKind int
+
+const (
+	// VanillaLSTM is the classic Hochreiter & Schmidhuber LSTM cell.
+	VanillaLSTM CellKind = iota
+	// PeepholeLSTM lets the input, forget and output gates look
+	// directly at the cell state through diagonal weights.
+	PeepholeLSTM
+	// GRU is a gated recurrent unit: two gates, no separate cell state.
+	GRU
+)
+
+// Cell is the recurrence a layer performs at each timestep. Exactly one
+// implementation backs each layer, chosen by the CellKind it was built
+// with.
+type Cell interface {
+	Fwd(input, prevHidden, prevCell *Node) (hidden, cell *Node)
+
+	// Learnables returns every weight this cell owns, for inclusion in
+	// charRNN.learnables().
+	Learnables() Nodes
+}
+
+func newCell(kind CellKind, l *lstm) Cell {
+	switch kind {
+	case PeepholeLSTM:
+		return &peepholeCell{l}
+	case GRU:
+		return &gruCell{l}
+	default:
+		return &vanillaCell{l}
+	}
+}
+
+// vanillaCell is the original hard-coded LSTM gate equations.
+type vanillaCell struct{ l *lstm }
+
+func (c *vanillaCell) Fwd(inputVector, prevHidden, prevCell *Node) (hidden, cell *Node) {
+	l := c.l
+
+	var h0, h1, inputGate *Node
+	h0 = Must(Mul(l.wix, inputVector))
+	h1 = Must(Mul(l.wih, prevHidden))
+	inputGate = Must(Sigmoid(addBias(Must(Add(h0, h1)), l.bias_i)))
+
+	var h2, h3, forgetGate *Node
+	h2 = Must(Mul(l.wfx, inputVector))
+	h3 = Must(Mul(l.wfh, prevHidden))
+	forgetGate = Must(Sigmoid(addBias(Must(Add(h2, h3)), l.bias_f)))
+
+	var h4, h5, outputGate *Node
+	h4 = Must(Mul(l.wox, inputVector))
+	h5 = Must(Mul(l.woh, prevHidden))
+	outputGate = Must(Sigmoid(addBias(Must(Add(h4, h5)), l.bias_o)))
+
+	var h6, h7, cellWrite *Node
+	h6 = Must(Mul(l.wcx, inputVector))
+	h7 = Must(Mul(l.wch, prevHidden))
+	cellWrite = Must(Tanh(addBias(Must(Add(h6, h7)), l.bias_c)))
+
+	// cell activations
+	var retain, write *Node
+	retain = Must(HadamardProd(forgetGate, prevCell))
+	write = Must(HadamardProd(inputGate, cellWrite))
+	cell = Must(Add(retain, write))
+	hidden = Must(HadamardProd(outputGate, Must(Tanh(cell))))
+	return
+}
+
+func (c *vanillaCell) Learnables() Nodes {
+	l := c.l
+	return Nodes{
+		l.wix, l.wih, l.bias_i,
+		l.wfx, l.wfh, l.bias_f,
+		l.wox, l.woh, l.bias_o,
+		l.wcx, l.wch, l.bias_c,
+	}
+}
+
+// peepholeCell is a vanilla LSTM whose input, forget and output gates
+// additionally see the cell state through diagonal weights wci/wcf/wco.
+type peepholeCell struct{ l *lstm }
+
+func (c *peepholeCell) Fwd(inputVector, prevHidden, prevCell *Node) (hidden, cell *Node) {
+	l := c.l
+
+	inputGate := Must(Sigmoid(addBias(
+		Must(Add(Must(Add(Must(Mul(l.wix, inputVector)), Must(Mul(l.wih, prevHidden)))), hadamardBias(prevCell, l.wci))),
+		l.bias_i,
+	)))
+
+	forgetGate := Must(Sigmoid(addBias(
+		Must(Add(Must(Add(Must(Mul(l.wfx, inputVector)), Must(Mul(l.wfh, prevHidden)))), hadamardBias(prevCell, l.wcf))),
+		l.bias_f,
+	)))
+
+	cellWrite := Must(Tanh(addBias(Must(Add(Must(Mul(l.wcx, inputVector)), Must(Mul(l.wch, prevHidden)))), l.bias_c)))
+
+	retain := Must(HadamardProd(forgetGate, prevCell))
+	write := Must(HadamardProd(inputGate, cellWrite))
+	cell = Must(Add(retain, write))
+
+	outputGate := Must(Sigmoid(addBias(
+		Must(Add(Must(Add(Must(Mul(l.wox, inputVector)), Must(Mul(l.woh, prevHidden)))), hadamardBias(cell, l.wco))),
+		l.bias_o,
+	)))
+	hidden = Must(HadamardProd(outputGate, Must(Tanh(cell))))
+	return
+}
+
+func (c *peepholeCell) Learnables() Nodes {
+	l := c.l
+	return Nodes{
+		l.wix, l.wih, l.bias_i,
+		l.wfx, l.wfh, l.bias_f,
+		l.wox, l.woh, l.bias_o,
+		l.wcx, l.wch, l.bias_c,
+		l.wci, l.wcf, l.wco,
+	}
+}
+
+// gruCell is a gated recurrent unit. It has no separate cell state, so
+// Fwd passes prevCell through unchanged.
+type gruCell struct{ l *lstm }
+
+func (c *gruCell) Fwd(inputVector, prevHidden, prevCell *Node) (hidden, cell *Node) {
+	l := c.l
+
+	reset := Must(Sigmoid(addBias(Must(Add(Must(Mul(l.wrx, inputVector)), Must(Mul(l.wrh, prevHidden)))), l.bias_r)))
+	update := Must(Sigmoid(addBias(Must(Add(Must(Mul(l.wzx, inputVector)), Must(Mul(l.wzh, prevHidden)))), l.bias_z)))
+
+	resetHidden := Must(HadamardProd(reset, prevHidden))
+	candidate := Must(Tanh(addBias(Must(Add(Must(Mul(l.whx, inputVector)), Must(Mul(l.whh, resetHidden)))), l.bias_h)))
+
+	hidden = Must(Add(prevHidden, Must(HadamardProd(update, Must(Sub(candidate, prevHidden))))))
+	cell = prevCell
+	return
+}
+
+func (c *gruCell) Learnables() Nodes {
+	l := c.l
+	return Nodes{
+		l.wrx, l.wrh, l.bias_r,
+		l.wzx, l.wzh, l.bias_z,
+		l.whx, l.whh, l.bias_h,
+	}
+}
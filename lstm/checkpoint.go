@@ -0,0 +1,258 @@
+package lstm
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+
+	. "gorgonia.org/gorgonia"
+	"gorgonia.org/tensor"
+)
+
+// modelMagic identifies the file format written by model.Save so that
+// Load can refuse to parse anything else.
+const modelMagic = "seensor-lstm-model-v1"
+
+// serializedLayer mirrors layer but holds plain float32 backing slices so
+// it can be gob-encoded without teaching gob about gorgonia's Value
+// interface. Only the fields matching the layer's CellKind are set.
+type serializedLayer struct {
+	Wix, Wih, BiasI []float32
+	Wfx, Wfh, BiasF []float32
+	Wox, Woh, BiasO []float32
+	Wcx, Wch, BiasC []float32
+
+	// peephole
+	Wci, Wcf, Wco []float32
+
+	// GRU
+	Wrx, Wrh, BiasR []float32
+	Wzx, Wzh, BiasZ []float32
+	Whx, Whh, BiasH []float32
+}
+
+// serializedModel is the on-disk representation written by model.Save.
+type serializedModel struct {
+	Magic string
+
+	InputSize, EmbeddingSize, OutputSize int
+	HiddenSizes                          []int
+	CellKinds                            []CellKind
+
+	Layers []serializedLayer
+
+	// Bidirectional mirrors model.Bidirectional. There is no separate
+	// LayersBack: NewBidirectionalLSTMModel ties lsBack to ls (see its
+	// doc comment), so model() just points the decoded model's lsBack
+	// back at its ls rather than decoding a second, redundant copy.
+	Bidirectional bool
+
+	Whd, BiasD []float32
+	Embedding  []float32
+}
+
+// denseFloats copies the backing float32 slice out of a Value so it can
+// be serialized independently of the tensor that owns it. v may be nil,
+// in which case it returns nil: not every field of layer is populated
+// for every CellKind.
+func denseFloats(v Value) []float32 {
+	if v == nil {
+		return nil
+	}
+	data := v.(*tensor.Dense).Data().([]float32)
+	out := make([]float32, len(data))
+	copy(out, data)
+	return out
+}
+
+// denseFromFloats rebuilds a tensor.Dense-backed Value from a flat
+// float32 slice and shape, copying the data so the decoded slice can be
+// discarded afterwards. A nil data returns a nil Value.
+func denseFromFloats(data []float32, shape ...int) Value {
+	if data == nil {
+		return nil
+	}
+	backing := make([]float32, len(data))
+	copy(backing, data)
+	return tensor.New(tensor.WithShape(shape...), tensor.WithBacking(backing))
+}
+
+func newSerializedModel(m *model) serializedModel {
+	sm := serializedModel{
+		Magic:         modelMagic,
+		InputSize:     m.inputSize,
+		EmbeddingSize: m.embeddingSize,
+		OutputSize:    m.outputSize,
+		HiddenSizes:   m.hiddenSizes,
+		CellKinds:     m.cellKinds,
+		Bidirectional: m.Bidirectional,
+		Whd:           denseFloats(m.whd),
+		BiasD:         denseFloats(m.bias_d),
+		Embedding:     denseFloats(m.embedding),
+	}
+	for _, l := range m.ls {
+		sm.Layers = append(sm.Layers, serializedLayer{
+			Wix: denseFloats(l.wix), Wih: denseFloats(l.wih), BiasI: denseFloats(l.bias_i),
+			Wfx: denseFloats(l.wfx), Wfh: denseFloats(l.wfh), BiasF: denseFloats(l.bias_f),
+			Wox: denseFloats(l.wox), Woh: denseFloats(l.woh), BiasO: denseFloats(l.bias_o),
+			Wcx: denseFloats(l.wcx), Wch: denseFloats(l.wch), BiasC: denseFloats(l.bias_c),
+
+			Wci: denseFloats(l.wci), Wcf: denseFloats(l.wcf), Wco: denseFloats(l.wco),
+
+			Wrx: denseFloats(l.wrx), Wrh: denseFloats(l.wrh), BiasR: denseFloats(l.bias_r),
+			Wzx: denseFloats(l.wzx), Wzh: denseFloats(l.wzh), BiasZ: denseFloats(l.bias_z),
+			Whx: denseFloats(l.whx), Whh: denseFloats(l.whh), BiasH: denseFloats(l.bias_h),
+		})
+	}
+	return sm
+}
+
+func (sm *serializedModel) model() (*model, error) {
+	if sm.Magic != modelMagic {
+		return nil, fmt.Errorf("lstm: not a model file (got magic %q)", sm.Magic)
+	}
+
+	m := &model{
+		inputSize:     sm.InputSize,
+		embeddingSize: sm.EmbeddingSize,
+		outputSize:    sm.OutputSize,
+		hiddenSizes:   sm.HiddenSizes,
+		cellKinds:     sm.CellKinds,
+	}
+	lastHiddenSize := sm.HiddenSizes[len(sm.HiddenSizes)-1]
+	m.whd = denseFromFloats(sm.Whd, sm.OutputSize, lastHiddenSize)
+	m.bias_d = denseFromFloats(sm.BiasD, sm.OutputSize)
+	m.embedding = denseFromFloats(sm.Embedding, sm.EmbeddingSize, sm.InputSize)
+
+	for depth, sl := range sm.Layers {
+		prevSize := sm.EmbeddingSize
+		if depth > 0 {
+			prevSize = sm.HiddenSizes[depth-1]
+		}
+		hiddenSize := sm.HiddenSizes[depth]
+		m.ls = append(m.ls, &layer{
+			wix: denseFromFloats(sl.Wix, hiddenSize, prevSize), wih: denseFromFloats(sl.Wih, hiddenSize, hiddenSize), bias_i: denseFromFloats(sl.BiasI, hiddenSize),
+			wfx: denseFromFloats(sl.Wfx, hiddenSize, prevSize), wfh: denseFromFloats(sl.Wfh, hiddenSize, hiddenSize), bias_f: denseFromFloats(sl.BiasF, hiddenSize),
+			wox: denseFromFloats(sl.Wox, hiddenSize, prevSize), woh: denseFromFloats(sl.Woh, hiddenSize, hiddenSize), bias_o: denseFromFloats(sl.BiasO, hiddenSize),
+			wcx: denseFromFloats(sl.Wcx, hiddenSize, prevSize), wch: denseFromFloats(sl.Wch, hiddenSize, hiddenSize), bias_c: denseFromFloats(sl.BiasC, hiddenSize),
+
+			wci: denseFromFloats(sl.Wci, hiddenSize), wcf: denseFromFloats(sl.Wcf, hiddenSize), wco: denseFromFloats(sl.Wco, hiddenSize),
+
+			wrx: denseFromFloats(sl.Wrx, hiddenSize, prevSize), wrh: denseFromFloats(sl.Wrh, hiddenSize, hiddenSize), bias_r: denseFromFloats(sl.BiasR, hiddenSize),
+			wzx: denseFromFloats(sl.Wzx, hiddenSize, prevSize), wzh: denseFromFloats(sl.Wzh, hiddenSize, hiddenSize), bias_z: denseFromFloats(sl.BiasZ, hiddenSize),
+			whx: denseFromFloats(sl.Whx, hiddenSize, prevSize), whh: denseFromFloats(sl.Whh, hiddenSize, hiddenSize), bias_h: denseFromFloats(sl.BiasH, hiddenSize),
+		})
+	}
+
+	if sm.Bidirectional {
+		m.Bidirectional = true
+		m.lsBack = m.ls
+	}
+	return m, nil
+}
+
+// Save writes every learnable weight, the hyperparameters needed to
+// rebuild the graph, to path using encoding/gob. The vocabulary and any
+// in-flight training state live on charRNN, see SaveCheckpoint.
+func (m *model) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sm := newSerializedModel(m)
+	return gob.NewEncoder(f).Encode(&sm)
+}
+
+// Load rebuilds a model previously written by Save.
+func Load(path string) (*model, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sm serializedModel
+	if err := gob.NewDecoder(f).Decode(&sm); err != nil {
+		return nil, err
+	}
+	return sm.model()
+}
+
+// checkpoint additionally captures the vocabulary and the running
+// prevHiddens/prevCells state so training can resume mid-sentence.
+type checkpoint struct {
+	Model       serializedModel
+	Vocabulary  *Vocabulary
+	Batch       int
+	PrevHiddens [][]float32
+	PrevCells   [][]float32
+}
+
+// SaveCheckpoint writes the model, vocabulary and current recurrent
+// state to path, allowing training to resume exactly where it left off.
+// The batch r.prevHiddens/prevCells were built for (1 for Learn,
+// r.batch for LearnBatch) is saved alongside them so LoadCheckpoint can
+// rebuild a charRNN of the same shape.
+func (r *charRNN) SaveCheckpoint(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cp := checkpoint{
+		Model:      newSerializedModel(r.model),
+		Vocabulary: r.Vocabulary,
+		Batch:      r.batch,
+	}
+	for _, h := range r.prevHiddens {
+		cp.PrevHiddens = append(cp.PrevHiddens, denseFloats(h.Value()))
+	}
+	for _, c := range r.prevCells {
+		cp.PrevCells = append(cp.PrevCells, denseFloats(c.Value()))
+	}
+
+	return gob.NewEncoder(f).Encode(&cp)
+}
+
+// LoadCheckpoint rebuilds a charRNN previously written by SaveCheckpoint,
+// including its vocabulary and recurrent state, ready to keep training.
+func LoadCheckpoint(path string) (*charRNN, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cp checkpoint
+	if err := gob.NewDecoder(f).Decode(&cp); err != nil {
+		return nil, err
+	}
+
+	m, err := cp.Model.model()
+	if err != nil {
+		return nil, err
+	}
+	batch := cp.Batch
+	if batch <= 0 {
+		batch = 1
+	}
+	r := NewCharRNN(m, cp.Vocabulary, batch)
+
+	for i, data := range cp.PrevHiddens {
+		src := tensor.New(tensor.WithShape(len(data)/batch, batch), tensor.WithBacking(data))
+		if err := src.CopyTo(r.prevHiddens[i].Value().(*tensor.Dense)); err != nil {
+			return nil, err
+		}
+	}
+	for i, data := range cp.PrevCells {
+		src := tensor.New(tensor.WithShape(len(data)/batch, batch), tensor.WithBacking(data))
+		if err := src.CopyTo(r.prevCells[i].Value().(*tensor.Dense)); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
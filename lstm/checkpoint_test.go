@@ -0,0 +1,123 @@
+package lstm
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"gorgonia.org/tensor"
+)
+
+// TestCheckpointRoundTripPreservesRecurrentState guards against the
+// shape mismatch LoadCheckpoint used to hit when rebuilding
+// prevHiddens/prevCells: SaveCheckpoint flattens each (hiddenSize, batch)
+// tensor to a plain slice, so LoadCheckpoint must rebuild it with a
+// matching shape before CopyTo-ing the data back in.
+func TestCheckpointRoundTripPreservesRecurrentState(t *testing.T) {
+	m := NewLSTMModel(3, 2, 3, []int{2}, nil, 0.1)
+	r := NewCharRNN(m, nil, 1)
+
+	want := []float32{1, 2}
+	seed := tensor.New(tensor.WithShape(2, 1), tensor.WithBacking(want))
+	if err := seed.CopyTo(r.prevHiddens[0].Value().(*tensor.Dense)); err != nil {
+		t.Fatalf("seed prevHiddens: %v", err)
+	}
+
+	f, err := ioutil.TempFile("", "checkpoint-*.gob")
+	if err != nil {
+		t.Fatalf("TempFile: %v", err)
+	}
+	path := f.Name()
+	f.Close()
+	defer os.Remove(path)
+
+	if err := r.SaveCheckpoint(path); err != nil {
+		t.Fatalf("SaveCheckpoint: %v", err)
+	}
+
+	loaded, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint: %v", err)
+	}
+
+	got := loaded.prevHiddens[0].Value().(*tensor.Dense).Data().([]float32)
+	for i, w := range want {
+		if got[i] != w {
+			t.Fatalf("prevHiddens[0][%d] = %v, want %v", i, got[i], w)
+		}
+	}
+}
+
+// TestCheckpointRoundTripPreservesBatch guards against LoadCheckpoint
+// hardcoding batch=1: a checkpoint saved mid-LearnBatch training (batch
+// > 1) must rebuild a charRNN of the same batch, or CopyTo fails on the
+// prevHiddens/prevCells shape mismatch.
+func TestCheckpointRoundTripPreservesBatch(t *testing.T) {
+	m := NewLSTMModel(3, 2, 3, []int{2}, nil, 0.1)
+	r := NewCharRNN(m, nil, 2)
+
+	want := []float32{1, 2, 3, 4}
+	seed := tensor.New(tensor.WithShape(2, 2), tensor.WithBacking(want))
+	if err := seed.CopyTo(r.prevHiddens[0].Value().(*tensor.Dense)); err != nil {
+		t.Fatalf("seed prevHiddens: %v", err)
+	}
+
+	f, err := ioutil.TempFile("", "checkpoint-*.gob")
+	if err != nil {
+		t.Fatalf("TempFile: %v", err)
+	}
+	path := f.Name()
+	f.Close()
+	defer os.Remove(path)
+
+	if err := r.SaveCheckpoint(path); err != nil {
+		t.Fatalf("SaveCheckpoint: %v", err)
+	}
+
+	loaded, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint: %v", err)
+	}
+
+	if loaded.batch != 2 {
+		t.Fatalf("loaded.batch = %d, want 2", loaded.batch)
+	}
+	got := loaded.prevHiddens[0].Value().(*tensor.Dense).Data().([]float32)
+	for i, w := range want {
+		if got[i] != w {
+			t.Fatalf("prevHiddens[0][%d] = %v, want %v", i, got[i], w)
+		}
+	}
+}
+
+// TestCheckpointRoundTripPreservesBidirectional guards against Save/Load
+// silently dropping model.Bidirectional: a model built with
+// NewBidirectionalLSTMModel must still report Bidirectional, with lsBack
+// wired back to ls, after a Save/Load round trip.
+func TestCheckpointRoundTripPreservesBidirectional(t *testing.T) {
+	m := NewBidirectionalLSTMModel(3, 2, 3, []int{2}, nil, 0.1)
+
+	f, err := ioutil.TempFile("", "model-*.gob")
+	if err != nil {
+		t.Fatalf("TempFile: %v", err)
+	}
+	path := f.Name()
+	f.Close()
+	defer os.Remove(path)
+
+	if err := m.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if !loaded.Bidirectional {
+		t.Fatalf("Load lost Bidirectional: got false, want true")
+	}
+	if loaded.lsBack == nil {
+		t.Fatalf("Load did not wire lsBack for a bidirectional model")
+	}
+}
@@ -0,0 +1,97 @@
+package lstm
+
+import (
+	"strconv"
+
+	. "gorgonia.org/gorgonia"
+	"gorgonia.org/tensor"
+)
+
+// Encode runs sentence through the model's LSTM stack in inference mode
+// and returns the final hidden state as a single fixed-size vector,
+// suitable as a context-aware embedding for downstream similarity
+// scoring. When the model is bidirectional, the backward stack's final
+// hidden (computed over the reversed sentence) is appended to the
+// forward one.
+func (r *charRNN) Encode(sentence []rune) []float32 {
+	forward := encodeDirection(r.model, r.model.ls, r.Vocabulary, sentence, false)
+	if !r.Bidirectional {
+		return forward
+	}
+	backward := encodeDirection(r.model, r.model.lsBack, r.Vocabulary, sentence, true)
+	return append(forward, backward...)
+}
+
+// encodeDirection unrolls layers one character at a time over its own
+// throwaway graph, feeding the reversed sentence when backward is set,
+// and returns the last layer's final hidden state. Every node carries an
+// explicit batch dimension of 1 (hiddenSize, 1) to match the (hiddenSize,
+// batch) convention Cell.Fwd and addBias assume.
+func encodeDirection(m *model, layers []*layer, vocab *Vocabulary, sentence []rune, backward bool) []float32 {
+	g := NewGraph()
+	embedding := NodeFromAny(g, m.embedding, WithName("Embedding"))
+
+	ls := make([]Cell, len(layers))
+	prevHiddens := make(Nodes, len(layers))
+	prevCells := make(Nodes, len(layers))
+	for i, l := range layers {
+		ls[i] = newCell(m.cellKinds[i], newLSTMLayer(g, l, "enc"+strconv.Itoa(i), m.cellKinds[i]))
+
+		hiddenSize := m.hiddenSizes[i]
+		zeroHidden := tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(hiddenSize, 1))
+		zeroCell := tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(hiddenSize, 1))
+		prevHiddens[i] = NewMatrix(g, Float32, WithShape(hiddenSize, 1), WithValue(zeroHidden))
+		prevCells[i] = NewMatrix(g, Float32, WithShape(hiddenSize, 1), WithValue(zeroCell))
+	}
+
+	inputTensor := tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(1, m.inputSize))
+	input := NewMatrix(g, tensor.Float32, WithShape(1, m.inputSize), WithValue(inputTensor))
+	embedded := Must(Mul(embedding, Must(Transpose(input))))
+
+	hiddens := make(Nodes, len(ls))
+	cells := make(Nodes, len(ls))
+	cur := embedded
+	for i, l := range ls {
+		hidden, cell := l.Fwd(cur, prevHiddens[i], prevCells[i])
+		hiddens[i], cells[i] = hidden, cell
+		cur = hidden
+	}
+
+	lastHiddenSize := m.hiddenSizes[len(m.hiddenSizes)-1]
+	if len(sentence) == 0 || len(ls) == 0 {
+		return make([]float32, lastHiddenSize)
+	}
+
+	machine := NewTapeMachine(g)
+	defer machine.Close()
+
+	n := len(sentence)
+	for step := 0; step < n; step++ {
+		idx := step
+		if backward {
+			idx = n - 1 - step
+		}
+
+		inputTensor.Zero()
+		inputTensor.SetF32(vocab.Index[sentence[idx]], 1.0)
+
+		if err := machine.RunAll(); err != nil {
+			panic(err)
+		}
+
+		for i := range prevHiddens {
+			if err := hiddens[i].Value().(*tensor.Dense).CopyTo(prevHiddens[i].Value().(*tensor.Dense)); err != nil {
+				panic(err)
+			}
+			if err := cells[i].Value().(*tensor.Dense).CopyTo(prevCells[i].Value().(*tensor.Dense)); err != nil {
+				panic(err)
+			}
+		}
+		machine.Reset()
+	}
+
+	final := hiddens[len(hiddens)-1].Value().Data().([]float32)
+	out := make([]float32, len(final))
+	copy(out, final)
+	return out
+}
@@ -0,0 +1,27 @@
+package lstm
+
+import "testing"
+
+func testVocabulary(chars string) *Vocabulary {
+	v := &Vocabulary{Index: make(map[rune]int)}
+	for i, c := range chars {
+		v.Index[c] = i
+		v.List = append(v.List, c)
+	}
+	return v
+}
+
+// TestEncodeRunsEndToEnd guards against the shape mismatch encodeDirection
+// used to hit once addBias started assuming matrix-shaped (hiddenSize,
+// batch) nodes: every node encodeDirection builds must carry the same
+// (hiddenSize, 1) shape, or machine.RunAll panics inside Cell.Fwd.
+func TestEncodeRunsEndToEnd(t *testing.T) {
+	m := NewLSTMModel(3, 2, 3, []int{2}, nil, 0.1)
+	vocab := testVocabulary("ab")
+	r := NewCharRNN(m, vocab, 1)
+
+	got := r.Encode([]rune("ab"))
+	if len(got) != 2 {
+		t.Fatalf("Encode returned %d floats, want 2 (the last layer's hidden size)", len(got))
+	}
+}
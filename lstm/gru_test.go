@@ -0,0 +1,27 @@
+package lstm
+
+import "testing"
+
+// TestGRUCellTrainsThroughModeLearn guards against gruCell.Fwd calling a
+// tensor helper that doesn't exist in gorgonia: the update-gate blend
+// must be expressed as prevHidden + update ⊙ (candidate - prevHidden)
+// rather than via an OnesLike-based (1-update) term.
+func TestGRUCellTrainsThroughModeLearn(t *testing.T) {
+	m := NewLSTMModel(3, 2, 3, []int{2}, []CellKind{GRU}, 0.1)
+	vocab := testVocabulary("abc")
+	r := NewCharRNN(m, vocab, 1)
+
+	cfg := TrainConfig{SolverKind: SGD, LearnRate: 0.1}
+	if err := r.ModeLearn(4, cfg); err != nil {
+		t.Fatalf("ModeLearn: %v", err)
+	}
+
+	solver, err := NewSolver(cfg)
+	if err != nil {
+		t.Fatalf("NewSolver: %v", err)
+	}
+
+	if _, _, err := r.Learn([]rune("abcabc"), 1, solver); err != nil {
+		t.Fatalf("Learn: %v", err)
+	}
+}
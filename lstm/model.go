@@ -22,6 +22,10 @@ type contextualError interface {
 	InstructionID() int
 }
 
+// layer holds the weights of a single recurrent layer. Not every field
+// is populated for every CellKind: vanilla and peephole LSTM use the
+// wix.../wcx... fields (peephole additionally uses wci/wcf/wco), while
+// GRU uses the wrx.../whh... fields instead and leaves the rest nil.
 type layer struct {
 	wix    Value
 	wih    Value
@@ -38,8 +42,26 @@ type layer struct {
 	wcx    Value
 	wch    Value
 	bias_c Value
+
+	// peephole connections, only set when the layer's CellKind is PeepholeLSTM
+	wci Value
+	wcf Value
+	wco Value
+
+	// GRU gates, only set when the layer's CellKind is GRU
+	wrx    Value
+	wrh    Value
+	bias_r Value
+	wzx    Value
+	wzh    Value
+	bias_z Value
+	whx    Value
+	whh    Value
+	bias_h Value
 }
 
+// lstm is the graph-bound counterpart of layer: one *Node per weight
+// that was non-nil on the layer it was built from.
 type lstm struct {
 	wix    *Node
 	wih    *Node
@@ -56,62 +78,91 @@ type lstm struct {
 	wcx    *Node
 	wch    *Node
 	bias_c *Node
+
+	wci *Node
+	wcf *Node
+	wco *Node
+
+	wrx    *Node
+	wrh    *Node
+	bias_r *Node
+	wzx    *Node
+	wzh    *Node
+	bias_z *Node
+	whx    *Node
+	whh    *Node
+	bias_h *Node
+}
+
+// addBias adds a length-n bias vector to an n×batch matrix, broadcasting
+// the bias across the batch dimension.
+func addBias(x, bias *Node) *Node {
+	return Must(BroadcastAdd(x, bias, nil, []byte{1}))
 }
 
-func newLSTMLayer(g *ExprGraph, l *layer, name string) *lstm {
+// hadamardBias is addBias's counterpart for the peephole cell's diagonal
+// connections: it multiplies an n×batch matrix elementwise by a
+// length-n vector, broadcasting the vector across the batch dimension.
+func hadamardBias(x, v *Node) *Node {
+	return Must(BroadcastHadamardProd(x, v, nil, []byte{1}))
+}
+
+func bindWeight(g *ExprGraph, v Value, name string) *Node {
+	if v == nil {
+		return nil
+	}
+	return NodeFromAny(g, v, WithName(name))
+}
+
+func newLSTMLayer(g *ExprGraph, l *layer, name string, kind CellKind) *lstm {
 	retVal := new(lstm)
-	retVal.wix = NodeFromAny(g, l.wix, WithName("wix_"+name))
-	retVal.wih = NodeFromAny(g, l.wih, WithName("wih_"+name))
-	retVal.bias_i = NodeFromAny(g, l.bias_i, WithName("bias_i_"+name))
 
-	retVal.wfx = NodeFromAny(g, l.wfx, WithName("wfx_"+name))
-	retVal.wfh = NodeFromAny(g, l.wfh, WithName("wfh_"+name))
-	retVal.bias_f = NodeFromAny(g, l.bias_f, WithName("bias_f_"+name))
+	retVal.wix = bindWeight(g, l.wix, "wix_"+name)
+	retVal.wih = bindWeight(g, l.wih, "wih_"+name)
+	retVal.bias_i = bindWeight(g, l.bias_i, "bias_i_"+name)
 
-	retVal.wox = NodeFromAny(g, l.wox, WithName("wox_"+name))
-	retVal.woh = NodeFromAny(g, l.woh, WithName("woh_"+name))
-	retVal.bias_o = NodeFromAny(g, l.bias_o, WithName("bias_o_"+name))
+	retVal.wfx = bindWeight(g, l.wfx, "wfx_"+name)
+	retVal.wfh = bindWeight(g, l.wfh, "wfh_"+name)
+	retVal.bias_f = bindWeight(g, l.bias_f, "bias_f_"+name)
 
-	retVal.wcx = NodeFromAny(g, l.wcx, WithName("wcx_"+name))
-	retVal.wch = NodeFromAny(g, l.wch, WithName("wch_"+name))
-	retVal.bias_c = NodeFromAny(g, l.bias_c, WithName("bias_c_"+name))
-	return retVal
-}
+	retVal.wox = bindWeight(g, l.wox, "wox_"+name)
+	retVal.woh = bindWeight(g, l.woh, "woh_"+name)
+	retVal.bias_o = bindWeight(g, l.bias_o, "bias_o_"+name)
 
-func (l *lstm) fwd(inputVector, prevHidden, prevCell *Node) (hidden, cell *Node) {
-	var h0, h1, inputGate *Node
-	h0 = Must(Mul(l.wix, inputVector))
-	h1 = Must(Mul(l.wih, prevHidden))
-	inputGate = Must(Sigmoid(Must(Add(Must(Add(h0, h1)), l.bias_i))))
-
-	var h2, h3, forgetGate *Node
-	h2 = Must(Mul(l.wfx, inputVector))
-	h3 = Must(Mul(l.wfh, prevHidden))
-	forgetGate = Must(Sigmoid(Must(Add(Must(Add(h2, h3)), l.bias_f))))
-
-	var h4, h5, outputGate *Node
-	h4 = Must(Mul(l.wox, inputVector))
-	h5 = Must(Mul(l.woh, prevHidden))
-	outputGate = Must(Sigmoid(Must(Add(Must(Add(h4, h5)), l.bias_o))))
-
-	var h6, h7, cellWrite *Node
-	h6 = Must(Mul(l.wcx, inputVector))
-	h7 = Must(Mul(l.wch, prevHidden))
-	cellWrite = Must(Tanh(Must(Add(Must(Add(h6, h7)), l.bias_c))))
-
-	// cell activations
-	var retain, write *Node
-	retain = Must(HadamardProd(forgetGate, prevCell))
-	write = Must(HadamardProd(inputGate, cellWrite))
-	cell = Must(Add(retain, write))
-	hidden = Must(HadamardProd(outputGate, Must(Tanh(cell))))
-	return
+	retVal.wcx = bindWeight(g, l.wcx, "wcx_"+name)
+	retVal.wch = bindWeight(g, l.wch, "wch_"+name)
+	retVal.bias_c = bindWeight(g, l.bias_c, "bias_c_"+name)
+
+	if kind == PeepholeLSTM {
+		retVal.wci = bindWeight(g, l.wci, "wci_"+name)
+		retVal.wcf = bindWeight(g, l.wcf, "wcf_"+name)
+		retVal.wco = bindWeight(g, l.wco, "wco_"+name)
+	}
+
+	if kind == GRU {
+		retVal.wrx = bindWeight(g, l.wrx, "wrx_"+name)
+		retVal.wrh = bindWeight(g, l.wrh, "wrh_"+name)
+		retVal.bias_r = bindWeight(g, l.bias_r, "bias_r_"+name)
+		retVal.wzx = bindWeight(g, l.wzx, "wzx_"+name)
+		retVal.wzh = bindWeight(g, l.wzh, "wzh_"+name)
+		retVal.bias_z = bindWeight(g, l.bias_z, "bias_z_"+name)
+		retVal.whx = bindWeight(g, l.whx, "whx_"+name)
+		retVal.whh = bindWeight(g, l.whh, "whh_"+name)
+		retVal.bias_h = bindWeight(g, l.bias_h, "bias_h_"+name)
+	}
+
+	return retVal
 }
 
 // single layer example
 type model struct {
 	ls []*layer
 
+	// lsBack is consumed back-to-front by Encode; only populated when
+	// Bidirectional is set. It is the same slice as ls (see
+	// NewBidirectionalLSTMModel), not an independently trained mirror.
+	lsBack []*layer
+
 	// decoder
 	whd    Value
 	bias_d Value
@@ -122,6 +173,16 @@ type model struct {
 	inputSize, embeddingSize, outputSize int
 	hiddenSizes                          []int
 
+	// cellKinds is parallel to hiddenSizes and selects the Cell
+	// implementation each layer (and its lsBack mirror, if any) uses.
+	cellKinds []CellKind
+
+	// Bidirectional makes Encode run lsBack over the reversed sequence
+	// and concatenate its final hidden to the forward one. It has no
+	// effect on the causal, next-character training graph built by
+	// ModeLearn.
+	Bidirectional bool
+
 	prefix string
 	free   bool
 }
@@ -133,13 +194,12 @@ type lstmOut struct {
 	probs *Node
 }
 
-func NewLSTMModel(inputSize, embeddingSize, outputSize int, hiddenSizes []int, stddev float64) *model {
-	m := new(model)
-	m.inputSize = inputSize
-	m.embeddingSize = embeddingSize
-	m.outputSize = outputSize
-	m.hiddenSizes = hiddenSizes
-
+// newLSTMLayers builds one layer per entry in hiddenSizes, each
+// consuming the previous layer's hidden size (or embeddingSize for the
+// first layer), using the Cell equations named by the matching entry in
+// cellKinds.
+func newLSTMLayers(embeddingSize int, hiddenSizes []int, cellKinds []CellKind, stddev float64) []*layer {
+	var ls []*layer
 	for depth := 0; depth < len(hiddenSizes); depth++ {
 		prevSize := embeddingSize
 		if depth > 0 {
@@ -147,7 +207,22 @@ func NewLSTMModel(inputSize, embeddingSize, outputSize int, hiddenSizes []int, s
 		}
 		hiddenSize := hiddenSizes[depth]
 		l := new(layer)
-		m.ls = append(m.ls, l) // add layer to model
+		ls = append(ls, l) // add layer to model
+
+		if cellKinds[depth] == GRU {
+			l.wrx = tensor.New(tensor.WithShape(hiddenSize, prevSize), tensor.WithBacking(Gaussian32(0.0, stddev, hiddenSize, prevSize)))
+			l.wrh = tensor.New(tensor.WithShape(hiddenSize, hiddenSize), tensor.WithBacking(Gaussian32(0.0, stddev, hiddenSize, hiddenSize)))
+			l.bias_r = tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(hiddenSize))
+
+			l.wzx = tensor.New(tensor.WithShape(hiddenSize, prevSize), tensor.WithBacking(Gaussian32(0.0, stddev, hiddenSize, prevSize)))
+			l.wzh = tensor.New(tensor.WithShape(hiddenSize, hiddenSize), tensor.WithBacking(Gaussian32(0.0, stddev, hiddenSize, hiddenSize)))
+			l.bias_z = tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(hiddenSize))
+
+			l.whx = tensor.New(tensor.WithShape(hiddenSize, prevSize), tensor.WithBacking(Gaussian32(0.0, stddev, hiddenSize, prevSize)))
+			l.whh = tensor.New(tensor.WithShape(hiddenSize, hiddenSize), tensor.WithBacking(Gaussian32(0.0, stddev, hiddenSize, hiddenSize)))
+			l.bias_h = tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(hiddenSize))
+			continue
+		}
 
 		// input gate weights
 
@@ -172,8 +247,42 @@ func NewLSTMModel(inputSize, embeddingSize, outputSize int, hiddenSizes []int, s
 		l.wcx = tensor.New(tensor.WithShape(hiddenSize, prevSize), tensor.WithBacking(Gaussian32(0.0, stddev, hiddenSize, prevSize)))
 		l.wch = tensor.New(tensor.WithShape(hiddenSize, hiddenSize), tensor.WithBacking(Gaussian32(0.0, stddev, hiddenSize, hiddenSize)))
 		l.bias_c = tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(hiddenSize))
+
+		if cellKinds[depth] == PeepholeLSTM {
+			l.wci = tensor.New(tensor.WithShape(hiddenSize), tensor.WithBacking(Gaussian32(0.0, stddev, hiddenSize)))
+			l.wcf = tensor.New(tensor.WithShape(hiddenSize), tensor.WithBacking(Gaussian32(0.0, stddev, hiddenSize)))
+			l.wco = tensor.New(tensor.WithShape(hiddenSize), tensor.WithBacking(Gaussian32(0.0, stddev, hiddenSize)))
+		}
+	}
+	return ls
+}
+
+// defaultCellKinds fills in VanillaLSTM for every layer when the caller
+// doesn't care to pick per-layer cells.
+func defaultCellKinds(hiddenSizes []int) []CellKind {
+	kinds := make([]CellKind, len(hiddenSizes))
+	for i := range kinds {
+		kinds[i] = VanillaLSTM
+	}
+	return kinds
+}
+
+// NewLSTMModel builds a stacked recurrent model. cellKinds picks the
+// Cell equations used by each entry in hiddenSizes; pass nil to use
+// VanillaLSTM throughout.
+func NewLSTMModel(inputSize, embeddingSize, outputSize int, hiddenSizes []int, cellKinds []CellKind, stddev float64) *model {
+	if cellKinds == nil {
+		cellKinds = defaultCellKinds(hiddenSizes)
 	}
 
+	m := new(model)
+	m.inputSize = inputSize
+	m.embeddingSize = embeddingSize
+	m.outputSize = outputSize
+	m.hiddenSizes = hiddenSizes
+	m.cellKinds = cellKinds
+	m.ls = newLSTMLayers(embeddingSize, hiddenSizes, cellKinds, stddev)
+
 	lastHiddenSize := hiddenSizes[len(hiddenSizes)-1]
 
 	m.whd = tensor.New(tensor.WithShape(outputSize, lastHiddenSize), tensor.WithBacking(Gaussian32(0.0, stddev, outputSize, lastHiddenSize)))
@@ -183,12 +292,31 @@ func NewLSTMModel(inputSize, embeddingSize, outputSize int, hiddenSizes []int, s
 	return m
 }
 
+// NewBidirectionalLSTMModel is NewLSTMModel plus Encode running the same
+// trained layers back-to-front over the reversed sentence, concatenating
+// that final hidden to the forward direction's. lsBack is deliberately
+// tied to ls rather than an independent layer set: ModeLearn only ever
+// backprops through the forward, causal graph, so a separate set of
+// backward weights would never receive a gradient and would stay at its
+// random initialization forever. Sharing ls means both directions of
+// Encode use the weights Learn/LearnBatch actually train.
+func NewBidirectionalLSTMModel(inputSize, embeddingSize, outputSize int, hiddenSizes []int, cellKinds []CellKind, stddev float64) *model {
+	if cellKinds == nil {
+		cellKinds = defaultCellKinds(hiddenSizes)
+	}
+
+	m := NewLSTMModel(inputSize, embeddingSize, outputSize, hiddenSizes, cellKinds, stddev)
+	m.Bidirectional = true
+	m.lsBack = m.ls
+	return m
+}
+
 type charRNN struct {
 	*model
 	*Vocabulary
 
 	g  *ExprGraph
-	ls []*lstm
+	ls []Cell
 
 	// decoder
 	whd    *Node
@@ -199,18 +327,31 @@ type charRNN struct {
 	prevHiddens Nodes
 	prevCells   Nodes
 
+	// batch is the number of sequences processed side by side; every
+	// tensor fed through the graph carries this as its batch dimension.
+	// Learn expects batch == 1; LearnBatch expects it to match
+	// len(sentences).
+	batch int
+
 	steps            int
+	window           int
+	trainConfig      TrainConfig
 	inputs           []*tensor.Dense
 	outputs          []*tensor.Dense
+	masks            []*tensor.Dense
 	previous         []*lstmOut
 	cost, perplexity *Node
 	machine          VM
 }
 
-func NewCharRNN(m *model, vocabulary *Vocabulary) *charRNN {
+// NewCharRNN builds a charRNN whose graph processes batch sequences at
+// a time. Pass batch == 1 for Predict/ModeInference/Learn; LearnBatch
+// needs batch == len(sentences).
+func NewCharRNN(m *model, vocabulary *Vocabulary, batch int) *charRNN {
 	r := new(charRNN)
 	r.model = m
 	r.Vocabulary = vocabulary
+	r.batch = batch
 	g := NewGraph()
 	r.g = g
 
@@ -218,14 +359,15 @@ func NewCharRNN(m *model, vocabulary *Vocabulary) *charRNN {
 	for depth := 0; depth < len(m.hiddenSizes); depth++ {
 		hiddenSize := m.hiddenSizes[depth]
 		layerID := strconv.Itoa(depth)
-		l := newLSTMLayer(r.g, r.model.ls[depth], layerID)
-		r.ls = append(r.ls, l)
+		kind := m.cellKinds[depth]
+		nodeLayer := newLSTMLayer(r.g, r.model.ls[depth], layerID, kind)
+		r.ls = append(r.ls, newCell(kind, nodeLayer))
 
 		// this is to simulate a default "previous" state
-		hiddenT := tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(hiddenSize))
-		cellT := tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(hiddenSize))
-		hidden := NewVector(g, Float32, WithName("prevHidden_"+layerID), WithShape(hiddenSize), WithValue(hiddenT))
-		cell := NewVector(g, Float32, WithName("prevCell_"+layerID), WithShape(hiddenSize), WithValue(cellT))
+		hiddenT := tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(hiddenSize, batch))
+		cellT := tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(hiddenSize, batch))
+		hidden := NewMatrix(g, Float32, WithName("prevHidden_"+layerID), WithShape(hiddenSize, batch), WithValue(hiddenT))
+		cell := NewMatrix(g, Float32, WithName("prevCell_"+layerID), WithShape(hiddenSize, batch), WithValue(cellT))
 
 		hiddens = append(hiddens, hidden)
 		cells = append(cells, cell)
@@ -243,22 +385,7 @@ func NewCharRNN(m *model, vocabulary *Vocabulary) *charRNN {
 
 func (r *charRNN) learnables() (retVal Nodes) {
 	for _, l := range r.ls {
-		lin := Nodes{
-			l.wix,
-			l.wih,
-			l.bias_i,
-			l.wfx,
-			l.wfh,
-			l.bias_f,
-			l.wox,
-			l.woh,
-			l.bias_o,
-			l.wcx,
-			l.wch,
-			l.bias_c,
-		}
-
-		retVal = append(retVal, lin...)
+		retVal = append(retVal, l.Learnables()...)
 	}
 
 	retVal = append(retVal, r.whd)
@@ -267,6 +394,11 @@ func (r *charRNN) learnables() (retVal Nodes) {
 	return
 }
 
+// fwd unrolls one more timestep of the recurrence for a batch of
+// r.batch sequences. inputs[j] (and so inputTensor here) carries
+// [batch, inputSize]; the embedding matmul transposes it to [inputSize,
+// batch] so the rest of the stack works in [hiddenSize, batch] space,
+// matching prevHiddens/prevCells.
 func (r *charRNN) fwd(prev *lstmOut) (inputTensor *tensor.Dense, retVal *lstmOut, err error) {
 	prevHiddens := r.prevHiddens
 	prevCells := r.prevCells
@@ -279,31 +411,22 @@ func (r *charRNN) fwd(prev *lstmOut) (inputTensor *tensor.Dense, retVal *lstmOut
 	for i, l := range r.ls {
 		var inputVector *Node
 		if i == 0 {
-			inputTensor = tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(r.inputSize))
-			input := NewVector(r.g, tensor.Float32, WithShape(r.inputSize), WithValue(inputTensor))
-			inputVector = Must(Mul(r.embedding, input))
+			inputTensor = tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(r.batch, r.inputSize))
+			input := NewMatrix(r.g, tensor.Float32, WithShape(r.batch, r.inputSize), WithValue(inputTensor))
+			inputVector = Must(Mul(r.embedding, Must(Transpose(input))))
 		} else {
 			inputVector = hiddens[i-1]
 		}
 		prevHidden := prevHiddens[i]
 		prevCell := prevCells[i]
 
-		hidden, cell := l.fwd(inputVector, prevHidden, prevCell)
+		hidden, cell := l.Fwd(inputVector, prevHidden, prevCell)
 		hiddens = append(hiddens, hidden)
 		cells = append(cells, cell)
 	}
-	lastHidden := hiddens[len(hiddens)-1]
-	var output *Node
-	if output, err = Mul(r.whd, lastHidden); err == nil {
-		if output, err = Add(output, r.bias_d); err != nil {
-			WithName("LAST HIDDEN")(lastHidden)
-			ioutil.WriteFile("err.dot", []byte(lastHidden.RestrictedToDot(3, 10)), 0644)
-			panic(fmt.Sprintf("ERROR: %v", err))
-		}
-	}
-
-	var probs *Node
-	probs = Must(SoftMax(output))
+	lastHidden := hiddens[len(hiddens)-1]                      // [hiddenSize, batch]
+	decoded := addBias(Must(Mul(r.whd, lastHidden)), r.bias_d) // [outputSize, batch]
+	probs := Must(SoftMax(Must(Transpose(decoded))))           // [batch, outputSize]
 
 	retVal = &lstmOut{
 		hiddens: hiddens,
@@ -342,13 +465,23 @@ func (r *charRNN) reset() {
 	}
 }
 
-func (r *charRNN) ModeLearn(steps int) (err error) {
-	inputs := make([]*tensor.Dense, steps-1)
-	outputs := make([]*tensor.Dense, steps-1)
-	previous := make([]*lstmOut, steps-1)
+// ModeLearn builds the training graph, unrolling window steps of the
+// recurrence where window is cfg.BPTTWindow (falling back to steps-1
+// when zero or larger), decoupling how far gradients backprop from the
+// steps argument itself.
+func (r *charRNN) ModeLearn(steps int, cfg TrainConfig) (err error) {
+	window := cfg.BPTTWindow
+	if window <= 0 || window > steps-1 {
+		window = steps - 1
+	}
+
+	inputs := make([]*tensor.Dense, window)
+	outputs := make([]*tensor.Dense, window)
+	masks := make([]*tensor.Dense, window)
+	previous := make([]*lstmOut, window)
 	var cost, perplexity *Node
 
-	for i := 0; i < steps-1; i++ {
+	for i := 0; i < window; i++ {
 		var loss, perp *Node
 		// cache
 
@@ -361,12 +494,28 @@ func (r *charRNN) ModeLearn(steps int) (err error) {
 			return
 		}
 
+		outputs[i] = tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(r.batch, r.outputSize))
+		output := NewMatrix(r.g, tensor.Float32, WithShape(r.batch, r.outputSize), WithValue(outputs[i]))
+
+		masks[i] = tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(r.batch))
+		mask := NewVector(r.g, tensor.Float32, WithShape(r.batch), WithValue(masks[i]))
+
+		// per-example cross entropy for this step, masked to zero out
+		// padded positions before it is folded into the scalar cost.
+		//
+		// log2prob is derived from logprob by a change of base rather
+		// than computing Log2(previous[i].probs) directly: gorgonia's
+		// unary ops can write their result back into the input's own
+		// backing buffer, so a second independent Log-family chain
+		// reading previous[i].probs would corrupt it for the first,
+		// turning perplexity into log2(ln(p)) instead of log2(p).
 		logprob := Must(Neg(Must(Log(previous[i].probs))))
-		outputs[i] = tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(r.outputSize))
-		output := NewVector(r.g, tensor.Float32, WithShape(r.outputSize), WithValue(outputs[i]))
-		loss = Must(Mul(logprob, output))
-		log2prob := Must(Neg(Must(Log2(previous[i].probs))))
-		perp = Must(Mul(log2prob, output))
+		perExample := Must(Sum(Must(HadamardProd(logprob, output)), 1))
+		loss = Must(Sum(Must(HadamardProd(perExample, mask))))
+
+		log2prob := Must(Div(logprob, NewConstant(float32(math.Ln2))))
+		perExamplePerp := Must(Sum(Must(HadamardProd(log2prob, output)), 1))
+		perp = Must(Sum(Must(HadamardProd(perExamplePerp, mask))))
 
 		if cost == nil {
 			cost = loss
@@ -383,8 +532,11 @@ func (r *charRNN) ModeLearn(steps int) (err error) {
 	}
 
 	r.steps = steps
+	r.window = window
+	r.trainConfig = cfg
 	r.inputs = inputs
 	r.outputs = outputs
+	r.masks = masks
 	r.previous = previous
 	r.cost = cost
 	r.perplexity = perplexity
@@ -494,7 +646,15 @@ func (r *charRNN) Learn(sentence []rune, iter int, solver Solver) (retCost, retP
 	n := len(sentence)
 
 	r.reset()
-	steps := r.steps - 1
+	steps := r.window
+
+	// Learn drives a single sequence, so every step of every window is
+	// a valid position; the mask is all ones for the whole call.
+	for j := 0; j < steps; j++ {
+		r.masks[j].Zero()
+		r.masks[j].SetF32(0, 1.0)
+	}
+
 	for x := 0; x < n-steps; x++ {
 		for j := 0; j < steps; j++ {
 			source := sentence[x+j]
@@ -518,7 +678,11 @@ func (r *charRNN) Learn(sentence []rune, iter int, solver Solver) (retCost, retP
 			return
 		}
 
-		err = solver.Step(r.learnables())
+		if err = ClipGradients(r.learnables(), r.trainConfig.GradientClipNorm); err != nil {
+			return
+		}
+
+		err = solver.Step(NodesToValueGrads(r.learnables()))
 		if err != nil {
 			return
 		}
@@ -536,3 +700,76 @@ func (r *charRNN) Learn(sentence []rune, iter int, solver Solver) (retCost, retP
 
 	return
 }
+
+// LearnBatch is Learn for r.batch sequences at once: sentences must have
+// exactly len(sentences) == r.batch entries. Sequences shorter than the
+// longest are padded with a zero mask, so the per-step loss only counts
+// positions that exist in the source sentence.
+func (r *charRNN) LearnBatch(sentences [][]rune, solver Solver) (retCost, retPerp []float64, err error) {
+	if len(sentences) != r.batch {
+		err = fmt.Errorf("lstm: LearnBatch got %d sentences, charRNN was built for batch %d", len(sentences), r.batch)
+		return
+	}
+
+	n := 0
+	for _, sentence := range sentences {
+		if len(sentence) > n {
+			n = len(sentence)
+		}
+	}
+
+	r.reset()
+	steps := r.window
+	for x := 0; x < n-steps; x++ {
+		// maskValid tallies the (batch, step) positions actually
+		// unmasked this iteration: with variable-length sentences it
+		// can be less than steps*r.batch once a shorter sentence's
+		// mask goes to zero, so it must be counted rather than assumed.
+		maskValid := 0
+		for j := 0; j < steps; j++ {
+			r.inputs[j].Zero()
+			r.outputs[j].Zero()
+			r.masks[j].Zero()
+
+			for b, sentence := range sentences {
+				if x+j+1 >= len(sentence) {
+					continue
+				}
+				source := sentence[x+j]
+				target := sentence[x+j+1]
+
+				r.inputs[j].SetF32(b*r.inputSize+r.Index[source], 1.0)
+				r.outputs[j].SetF32(b*r.outputSize+r.Index[target], 1.0)
+				r.masks[j].SetF32(b, 1.0)
+				maskValid++
+			}
+		}
+
+		if err = r.machine.RunAll(); err != nil {
+			if ctxerr, ok := err.(contextualError); ok {
+				ioutil.WriteFile("FAIL.dot", []byte(ctxerr.Node().RestrictedToDot(3, 3)), 0644)
+			}
+			return
+		}
+
+		if err = ClipGradients(r.learnables(), r.trainConfig.GradientClipNorm); err != nil {
+			return
+		}
+
+		if err = solver.Step(NodesToValueGrads(r.learnables())); err != nil {
+			return
+		}
+
+		if sv, ok := r.perplexity.Value().(Scalar); ok && maskValid > 0 {
+			v := sv.Data().(float32)
+			retPerp = append(retPerp, math.Pow(2, float64(v)/float64(maskValid)))
+		}
+		if cv, ok := r.cost.Value().(Scalar); ok {
+			retCost = append(retCost, float64(cv.Data().(float32)))
+		}
+		r.feedback(0)
+		r.machine.Reset()
+	}
+
+	return
+}
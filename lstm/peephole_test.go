@@ -0,0 +1,29 @@
+package lstm
+
+import "testing"
+
+// TestPeepholeCellTrainsThroughModeLearn guards against the shape
+// mismatch in peepholeCell.Fwd's diagonal wci/wcf/wco connections: once
+// ModeLearn moved prevCell/cell to (hiddenSize, batch) matrices, an
+// unbroadcast HadamardProd against the (hiddenSize) peephole weights
+// failed machine.RunAll for every PeepholeLSTM layer, not just batched
+// ones.
+func TestPeepholeCellTrainsThroughModeLearn(t *testing.T) {
+	m := NewLSTMModel(3, 2, 3, []int{2}, []CellKind{PeepholeLSTM}, 0.1)
+	vocab := testVocabulary("abc")
+	r := NewCharRNN(m, vocab, 1)
+
+	cfg := TrainConfig{SolverKind: SGD, LearnRate: 0.1}
+	if err := r.ModeLearn(4, cfg); err != nil {
+		t.Fatalf("ModeLearn: %v", err)
+	}
+
+	solver, err := NewSolver(cfg)
+	if err != nil {
+		t.Fatalf("NewSolver: %v", err)
+	}
+
+	if _, _, err := r.Learn([]rune("abcabc"), 1, solver); err != nil {
+		t.Fatalf("Learn: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package lstm
+
+import (
+	"math"
+	"testing"
+)
+
+// TestLearnPerplexityIsFinite guards against ModeLearn feeding
+// previous[i].probs into two independent Log-family chains (Log for
+// cost, Log2 for perplexity): gorgonia's unary ops can write their
+// result back into the input's own backing buffer, so the second chain
+// read probs after the first had already overwritten it with ln(p),
+// making every reported perplexity NaN regardless of model shape, batch
+// size or solver.
+func TestLearnPerplexityIsFinite(t *testing.T) {
+	m := NewLSTMModel(3, 2, 3, []int{2}, nil, 0.1)
+	vocab := testVocabulary("abc")
+	r := NewCharRNN(m, vocab, 1)
+
+	cfg := TrainConfig{SolverKind: SGD, LearnRate: 0.1}
+	if err := r.ModeLearn(4, cfg); err != nil {
+		t.Fatalf("ModeLearn: %v", err)
+	}
+
+	solver, err := NewSolver(cfg)
+	if err != nil {
+		t.Fatalf("NewSolver: %v", err)
+	}
+
+	_, retPerp, err := r.Learn([]rune("abcabc"), 1, solver)
+	if err != nil {
+		t.Fatalf("Learn: %v", err)
+	}
+	if len(retPerp) == 0 {
+		t.Fatalf("Learn returned no perplexity values")
+	}
+	for i, p := range retPerp {
+		if math.IsNaN(p) || math.IsInf(p, 0) {
+			t.Fatalf("retPerp[%d] = %v, want a finite perplexity", i, p)
+		}
+	}
+}
+
+// TestLearnBatchPerplexityIsFinite is TestLearnPerplexityIsFinite for
+// LearnBatch, whose per-step cost/perplexity graph is shared with Learn
+// via ModeLearn.
+func TestLearnBatchPerplexityIsFinite(t *testing.T) {
+	m := NewLSTMModel(3, 2, 3, []int{2}, nil, 0.1)
+	vocab := testVocabulary("abc")
+	r := NewCharRNN(m, vocab, 2)
+
+	cfg := TrainConfig{SolverKind: SGD, LearnRate: 0.1}
+	if err := r.ModeLearn(4, cfg); err != nil {
+		t.Fatalf("ModeLearn: %v", err)
+	}
+
+	solver, err := NewSolver(cfg)
+	if err != nil {
+		t.Fatalf("NewSolver: %v", err)
+	}
+
+	_, retPerp, err := r.LearnBatch([][]rune{[]rune("abcabc"), []rune("ab")}, solver)
+	if err != nil {
+		t.Fatalf("LearnBatch: %v", err)
+	}
+	if len(retPerp) == 0 {
+		t.Fatalf("LearnBatch returned no perplexity values")
+	}
+	for i, p := range retPerp {
+		if math.IsNaN(p) || math.IsInf(p, 0) {
+			t.Fatalf("retPerp[%d] = %v, want a finite perplexity", i, p)
+		}
+	}
+}
+
+// TestLearnBatchPerplexityDivisorMatchesMaskCount guards against
+// retPerp dividing by the fixed batch*(n-1) instead of the number of
+// (batch, step) positions the mask actually left unmasked that
+// iteration. With a short sentence ("ab") padded out against a long one
+// ("abcabcabcabc"), most windows have far fewer than batch*(steps)
+// valid positions, so a static divisor silently clamps every reported
+// perplexity into a narrow band regardless of the model's predictions.
+// retCost and retPerp share the same masked-sum machinery (perplexity
+// is cost's per-step sum expressed in log2 rather than ln), so the
+// expected divisor can be checked by re-deriving retPerp from retCost
+// and an independently counted mask total.
+func TestLearnBatchPerplexityDivisorMatchesMaskCount(t *testing.T) {
+	m := NewLSTMModel(3, 2, 3, []int{2}, nil, 0.1)
+	vocab := testVocabulary("abc")
+	r := NewCharRNN(m, vocab, 2)
+
+	cfg := TrainConfig{SolverKind: SGD, LearnRate: 0.1, BPTTWindow: 2}
+	if err := r.ModeLearn(4, cfg); err != nil {
+		t.Fatalf("ModeLearn: %v", err)
+	}
+
+	solver, err := NewSolver(cfg)
+	if err != nil {
+		t.Fatalf("NewSolver: %v", err)
+	}
+
+	sentences := [][]rune{[]rune("abcabcabcabc"), []rune("ab")}
+	retCost, retPerp, err := r.LearnBatch(sentences, solver)
+	if err != nil {
+		t.Fatalf("LearnBatch: %v", err)
+	}
+
+	maskCounts := maskCountsForWindow(sentences, r.window)
+	if len(maskCounts) != len(retPerp) {
+		t.Fatalf("got %d retPerp values, want %d (one per x-iteration)", len(retPerp), len(maskCounts))
+	}
+
+	for i, count := range maskCounts {
+		want := math.Pow(2, retCost[i]/(math.Ln2*float64(count)))
+		if math.Abs(retPerp[i]-want) > 1e-6 {
+			t.Fatalf("retPerp[%d] = %v, want %v (mask count %d, not a fixed batch*(n-1) divisor)", i, retPerp[i], want, count)
+		}
+	}
+}
+
+// maskCountsForWindow reproduces LearnBatch's x/j/b loop structure to
+// independently count how many (batch, step) positions are unmasked at
+// each x-iteration, without touching any model internals.
+func maskCountsForWindow(sentences [][]rune, steps int) []int {
+	n := 0
+	for _, s := range sentences {
+		if len(s) > n {
+			n = len(s)
+		}
+	}
+
+	var counts []int
+	for x := 0; x < n-steps; x++ {
+		count := 0
+		for j := 0; j < steps; j++ {
+			for _, s := range sentences {
+				if x+j+1 >= len(s) {
+					continue
+				}
+				count++
+			}
+		}
+		counts = append(counts, count)
+	}
+	return counts
+}
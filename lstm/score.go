@@ -0,0 +1,48 @@
+package lstm
+
+import (
+	"math"
+
+	"gorgonia.org/tensor"
+)
+
+// Observe feeds x, the one-hot (or soft) encoding of the next observed
+// token, through a charRNN compiled with ModeInference and returns its
+// per-token surprise, -log2(p(x)), under the distribution the model
+// predicted from everything fed so far. The state update mirrors
+// Predict: probs are read before feedback carries the recurrent state
+// forward, and x is queued as the input RunAll consumes next call.
+func (r *charRNN) Observe(x []float32) float32 {
+	if err := r.machine.RunAll(); err != nil {
+		panic(err)
+	}
+
+	probs := r.previous[0].probs.Value().(*tensor.Dense).Data().([]float32)
+	var p float32
+	for i, v := range x {
+		p += v * probs[i]
+	}
+	surprise := float32(-math.Log2(float64(p)))
+
+	r.feedback(0)
+	r.machine.Reset()
+
+	r.inputs[0].Zero()
+	for i, v := range x {
+		if v != 0 {
+			r.inputs[0].SetF32(i, v)
+		}
+	}
+
+	return surprise
+}
+
+// Reset clears the recurrent state and queues the same implicit
+// start-of-stream input Predict uses for an empty sentence (index 0),
+// restarting the stream Observe feeds from scratch. Callers must Reset
+// before the first Observe.
+func (r *charRNN) Reset() {
+	r.reset()
+	r.inputs[0].Zero()
+	r.inputs[0].SetF32(0, 1.0)
+}
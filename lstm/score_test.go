@@ -0,0 +1,57 @@
+package lstm
+
+import "testing"
+
+// TestObserveScoresLearnedPatternsAsLessSurprising is an end-to-end
+// ModeInference+Observe round trip: Observe reads previous[0].probs
+// before feedback carries the recurrent state forward, so training on a
+// strict alternation and then observing that same alternation should
+// report much lower average surprise than observing a sequence that
+// breaks it.
+func TestObserveScoresLearnedPatternsAsLessSurprising(t *testing.T) {
+	vocab := testVocabulary("ab")
+	m := NewLSTMModel(2, 4, 2, []int{8}, nil, 0.1)
+	r := NewCharRNN(m, vocab, 1)
+
+	cfg := TrainConfig{SolverKind: Adam, LearnRate: 0.05}
+	if err := r.ModeLearn(8, cfg); err != nil {
+		t.Fatalf("ModeLearn: %v", err)
+	}
+	solver, err := NewSolver(cfg)
+	if err != nil {
+		t.Fatalf("NewSolver: %v", err)
+	}
+
+	pattern := []rune("abababababababab")
+	for i := 0; i < 200; i++ {
+		if _, _, err := r.Learn(pattern, 1, solver); err != nil {
+			t.Fatalf("Learn: %v", err)
+		}
+	}
+
+	if err := r.ModeInference(); err != nil {
+		t.Fatalf("ModeInference: %v", err)
+	}
+
+	onehot := func(c rune) []float32 {
+		x := make([]float32, len(vocab.List))
+		x[vocab.Index[c]] = 1
+		return x
+	}
+
+	observeAvgSurprise := func(seq []rune) float32 {
+		r.Reset()
+		var sum float32
+		for _, c := range seq {
+			sum += r.Observe(onehot(c))
+		}
+		return sum / float32(len(seq))
+	}
+
+	learned := observeAvgSurprise([]rune("ababababab"))
+	novel := observeAvgSurprise([]rune("aaaaaaaaaa"))
+
+	if learned >= novel {
+		t.Fatalf("average surprise for the learned alternation (%v) was not lower than for the broken pattern (%v)", learned, novel)
+	}
+}
@@ -0,0 +1,275 @@
+package lstm
+
+import (
+	"fmt"
+	"math"
+
+	. "gorgonia.org/gorgonia"
+)
+
+// SolverKind selects which optimizer NewSolver builds.
+type SolverKind int
+
+const (
+	SGD SolverKind = iota
+	RMSProp
+	Adam
+	LBFGS
+)
+
+// TrainConfig bundles the knobs that control how ModeLearn builds its
+// graph and how Learn steps the optimizer, so callers no longer have to
+// hard-code an unclipped vanilla-SGD, full-BPTT training loop.
+type TrainConfig struct {
+	// GradientClipNorm caps the global L2 norm of r.learnables()'
+	// gradients before every solver.Step. Zero disables clipping.
+	GradientClipNorm float64
+
+	// SolverKind selects the optimizer built by NewSolver.
+	SolverKind SolverKind
+
+	// LearnRate applies to every SolverKind. L2Reg and Clip apply to
+	// SGD, RMSProp and Adam; L1Reg applies to SGD only. LBFGS uses none
+	// of L1Reg, L2Reg and Clip.
+	LearnRate, L1Reg, L2Reg, Clip float64
+
+	// Rho and Eps configure RMSProp; Eps is also passed to Adam.
+	Rho, Eps float64
+
+	// Beta1 and Beta2 configure Adam.
+	Beta1, Beta2 float64
+
+	// LBFGSHistory is the number of (s, y) pairs the L-BFGS two-loop
+	// recursion keeps. Ignored unless SolverKind is LBFGS. Defaults to
+	// 10 when zero.
+	LBFGSHistory int
+
+	// BPTTWindow truncates the graph ModeLearn unrolls to this many
+	// steps, independent of the steps argument passed to ModeLearn.
+	// Zero (or a value >= steps-1) backprops through the full window.
+	BPTTWindow int
+}
+
+// NewSolver builds the Solver selected by cfg.SolverKind.
+func NewSolver(cfg TrainConfig) (Solver, error) {
+	switch cfg.SolverKind {
+	case SGD:
+		return NewVanillaSolver(WithLearnRate(cfg.LearnRate), WithL1Reg(cfg.L1Reg), WithL2Reg(cfg.L2Reg), WithClip(cfg.Clip)), nil
+	case RMSProp:
+		return NewRMSPropSolver(WithLearnRate(cfg.LearnRate), WithRho(cfg.Rho), WithEps(cfg.Eps), WithL2Reg(cfg.L2Reg), WithClip(cfg.Clip)), nil
+	case Adam:
+		return NewAdamSolver(WithLearnRate(cfg.LearnRate), WithBeta1(cfg.Beta1), WithBeta2(cfg.Beta2), WithEps(cfg.Eps), WithL2Reg(cfg.L2Reg), WithClip(cfg.Clip)), nil
+	case LBFGS:
+		return newLBFGSSolver(cfg), nil
+	default:
+		return nil, fmt.Errorf("lstm: unknown solver kind %v", cfg.SolverKind)
+	}
+}
+
+// ClipGradients rescales the gradients of model in place so their
+// combined L2 norm does not exceed threshold. Call it after
+// machine.RunAll() has populated the gradients and before solver.Step.
+// threshold <= 0 disables clipping.
+func ClipGradients(model Nodes, threshold float64) error {
+	if threshold <= 0 {
+		return nil
+	}
+
+	datas := make([][]float32, len(model))
+	var sumSq float64
+	for i, n := range model {
+		g, err := n.Grad()
+		if err != nil {
+			return err
+		}
+		data, ok := g.Data().([]float32)
+		if !ok {
+			return fmt.Errorf("lstm: gradient of %v is not float32-backed", n)
+		}
+		datas[i] = data
+		for _, v := range data {
+			sumSq += float64(v) * float64(v)
+		}
+	}
+
+	norm := math.Sqrt(sumSq)
+	if norm <= threshold {
+		return nil
+	}
+
+	scale := float32(threshold / norm)
+	for _, data := range datas {
+		for i := range data {
+			data[i] *= scale
+		}
+	}
+	return nil
+}
+
+// lbfgsSolver is a limited-memory BFGS Solver implementing the standard
+// two-loop recursion. It is not safe for concurrent use.
+type lbfgsSolver struct {
+	history int
+	lr      float64
+
+	// s[k] = x_{k+1} - x_k, y[k] = g_{k+1} - g_k, rho[k] = 1/(s[k]. y[k]),
+	// stored as parallel ring buffers of per-parameter flat slices.
+	s, y []([][]float32)
+	rho  []float64
+
+	prevX, prevG [][]float32
+}
+
+func newLBFGSSolver(cfg TrainConfig) *lbfgsSolver {
+	history := cfg.LBFGSHistory
+	if history <= 0 {
+		history = 10
+	}
+	lr := cfg.LearnRate
+	if lr <= 0 {
+		lr = 1
+	}
+	return &lbfgsSolver{history: history, lr: lr}
+}
+
+// Step applies one L-BFGS update to model using its current gradients.
+// It satisfies Solver the same way NewVanillaSolver/NewRMSPropSolver/
+// NewAdamSolver do: model is the []ValueGrad machine.RunAll() populated,
+// not the Nodes charRNN.learnables() returns.
+func (l *lbfgsSolver) Step(model []ValueGrad) (err error) {
+	x := make([][]float32, len(model))
+	g := make([][]float32, len(model))
+	for i, n := range model {
+		v, ok := n.Value().Data().([]float32)
+		if !ok {
+			return fmt.Errorf("lstm: lbfgs: param %v is not float32-backed", n)
+		}
+		x[i] = v
+
+		gn, err := n.Grad()
+		if err != nil {
+			return err
+		}
+		gd, ok := gn.Data().([]float32)
+		if !ok {
+			return fmt.Errorf("lstm: lbfgs: gradient of %v is not float32-backed", n)
+		}
+		g[i] = gd
+	}
+
+	if l.prevX != nil {
+		s := subAll(x, l.prevX)
+		y := subAll(g, l.prevG)
+		if sy := dotAll(s, y); sy > 0 {
+			l.s = append(l.s, s)
+			l.y = append(l.y, y)
+			l.rho = append(l.rho, 1/sy)
+			if len(l.s) > l.history {
+				l.s = l.s[1:]
+				l.y = l.y[1:]
+				l.rho = l.rho[1:]
+			}
+		}
+	}
+
+	dir := l.direction(g)
+
+	// x aliases the nodes' own backing arrays, so prevX must be
+	// snapshotted before the update loop mutates them in place -
+	// otherwise the next Step sees x == prevX and s, sy are always 0.
+	l.prevX = cloneAll(x)
+	l.prevG = cloneAll(g)
+
+	lr := float32(l.lr)
+	for i := range x {
+		for j := range x[i] {
+			x[i][j] += lr * dir[i][j]
+		}
+	}
+
+	return nil
+}
+
+// direction implements the two-loop recursion, returning the descent
+// direction r = -H_k g for the current gradient g.
+func (l *lbfgsSolver) direction(g [][]float32) [][]float32 {
+	q := cloneAll(g)
+	m := len(l.s)
+	alpha := make([]float64, m)
+
+	for i := m - 1; i >= 0; i-- {
+		alpha[i] = l.rho[i] * dotAll(l.s[i], q)
+		axpyAll(q, l.y[i], -alpha[i])
+	}
+
+	gamma := 1.0
+	if m > 0 {
+		last := m - 1
+		sy := dotAll(l.s[last], l.y[last])
+		yy := dotAll(l.y[last], l.y[last])
+		if yy > 0 {
+			gamma = sy / yy
+		}
+	}
+	r := cloneAll(q)
+	scaleAll(r, gamma)
+
+	for i := 0; i < m; i++ {
+		beta := l.rho[i] * dotAll(l.y[i], r)
+		axpyAll(r, l.s[i], alpha[i]-beta)
+	}
+
+	for i := range r {
+		for j := range r[i] {
+			r[i][j] = -r[i][j]
+		}
+	}
+	return r
+}
+
+func cloneAll(a [][]float32) [][]float32 {
+	out := make([][]float32, len(a))
+	for i, s := range a {
+		out[i] = append([]float32(nil), s...)
+	}
+	return out
+}
+
+func subAll(a, b [][]float32) [][]float32 {
+	out := make([][]float32, len(a))
+	for i := range a {
+		out[i] = make([]float32, len(a[i]))
+		for j := range a[i] {
+			out[i][j] = a[i][j] - b[i][j]
+		}
+	}
+	return out
+}
+
+func dotAll(a, b [][]float32) float64 {
+	var sum float64
+	for i := range a {
+		for j := range a[i] {
+			sum += float64(a[i][j]) * float64(b[i][j])
+		}
+	}
+	return sum
+}
+
+func axpyAll(dst, x [][]float32, alpha float64) {
+	a := float32(alpha)
+	for i := range dst {
+		for j := range dst[i] {
+			dst[i][j] += a * x[i][j]
+		}
+	}
+}
+
+func scaleAll(a [][]float32, s float64) {
+	f := float32(s)
+	for i := range a {
+		for j := range a[i] {
+			a[i][j] *= f
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package lstm
+
+import (
+	"math"
+	"testing"
+
+	. "gorgonia.org/gorgonia"
+	"gorgonia.org/tensor"
+)
+
+// TestLBFGSSolverAccumulatesHistory guards against the bug where Step
+// snapshotted prevX/prevG after mutating the parameter in place: since x
+// aliases the node's own backing array, that made every second Step see
+// x == prevX, so s and sy were always the zero vector and no (s, y)
+// pair was ever added to the history.
+func TestLBFGSSolverAccumulatesHistory(t *testing.T) {
+	g := NewGraph()
+	xVal := tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(1), tensor.WithBacking([]float32{5}))
+	x := NewVector(g, tensor.Float32, WithName("x"), WithShape(1), WithValue(xVal))
+
+	loss := Must(Mul(x, x))
+	if _, err := Grad(loss, x); err != nil {
+		t.Fatalf("Grad: %v", err)
+	}
+
+	machine := NewTapeMachine(g, BindDualValues(x))
+	defer machine.Close()
+
+	solver := newLBFGSSolver(TrainConfig{SolverKind: LBFGS, LearnRate: 0.1})
+
+	for i := 0; i < 2; i++ {
+		if err := machine.RunAll(); err != nil {
+			t.Fatalf("RunAll: %v", err)
+		}
+		if err := solver.Step(NodesToValueGrads(Nodes{x})); err != nil {
+			t.Fatalf("Step: %v", err)
+		}
+		machine.Reset()
+
+		// machine.Reset() only rewinds the program counter and frees
+		// scratch registers; it does not zero a bound node's derivative,
+		// which RunAll accumulates into rather than overwrites. Without
+		// this, the second iteration's gradient would be g1+g2 instead
+		// of g2, so s.y would go negative and no history pair would be
+		// kept.
+		if gr, err := x.Grad(); err == nil {
+			gr.(*tensor.Dense).Zero()
+		}
+	}
+
+	if len(solver.s) == 0 {
+		t.Fatalf("expected L-BFGS history to hold at least one (s, y) pair after two Step calls, got 0")
+	}
+}
+
+// TestClipGradientsRescalesToThreshold exercises ClipGradients directly:
+// a gradient whose global L2 norm exceeds threshold must be rescaled
+// in place so the norm lands at threshold, with direction preserved.
+func TestClipGradientsRescalesToThreshold(t *testing.T) {
+	g := NewGraph()
+	xVal := tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(2), tensor.WithBacking([]float32{3, 4}))
+	x := NewVector(g, tensor.Float32, WithName("x"), WithShape(2), WithValue(xVal))
+
+	// loss = sum(x*x), so grad x = 2x = [6, 8], norm 10.
+	loss := Must(Sum(Must(HadamardProd(x, x))))
+	if _, err := Grad(loss, x); err != nil {
+		t.Fatalf("Grad: %v", err)
+	}
+
+	machine := NewTapeMachine(g, BindDualValues(x))
+	defer machine.Close()
+	if err := machine.RunAll(); err != nil {
+		t.Fatalf("RunAll: %v", err)
+	}
+
+	const threshold = 2.0
+	if err := ClipGradients(Nodes{x}, threshold); err != nil {
+		t.Fatalf("ClipGradients: %v", err)
+	}
+
+	gr, err := x.Grad()
+	if err != nil {
+		t.Fatalf("Grad: %v", err)
+	}
+	data := gr.Data().([]float32)
+
+	var sumSq float64
+	for _, v := range data {
+		sumSq += float64(v) * float64(v)
+	}
+	norm := math.Sqrt(sumSq)
+	if math.Abs(norm-threshold) > 1e-4 {
+		t.Fatalf("rescaled gradient norm = %v, want %v", norm, threshold)
+	}
+
+	// direction must be preserved: original gradient was [6, 8], so the
+	// rescaled one must still have the second component 4/3 the first.
+	if want := data[0] * 4 / 3; math.Abs(float64(data[1]-want)) > 1e-4 {
+		t.Fatalf("rescaled gradient = %v, direction not preserved", data)
+	}
+}
+
+// TestClipGradientsBelowThresholdIsNoop guards the other branch:
+// gradients already within threshold must be left untouched.
+func TestClipGradientsBelowThresholdIsNoop(t *testing.T) {
+	g := NewGraph()
+	xVal := tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(2), tensor.WithBacking([]float32{1, 1}))
+	x := NewVector(g, tensor.Float32, WithName("x"), WithShape(2), WithValue(xVal))
+
+	loss := Must(Sum(Must(HadamardProd(x, x))))
+	if _, err := Grad(loss, x); err != nil {
+		t.Fatalf("Grad: %v", err)
+	}
+
+	machine := NewTapeMachine(g, BindDualValues(x))
+	defer machine.Close()
+	if err := machine.RunAll(); err != nil {
+		t.Fatalf("RunAll: %v", err)
+	}
+
+	gr, err := x.Grad()
+	if err != nil {
+		t.Fatalf("Grad: %v", err)
+	}
+	want := append([]float32(nil), gr.Data().([]float32)...)
+
+	if err := ClipGradients(Nodes{x}, 100); err != nil {
+		t.Fatalf("ClipGradients: %v", err)
+	}
+
+	got := gr.Data().([]float32)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("ClipGradients changed an under-threshold gradient: got %v, want %v", got, want)
+		}
+	}
+}
+
+// TestModeLearnBPTTWindowTruncatesUnroll guards cfg.BPTTWindow actually
+// decoupling the unrolled graph from steps: ModeLearn must unroll
+// exactly cfg.BPTTWindow steps (not steps-1), and Learn must iterate
+// n-window times over the sequence rather than n-(steps-1) times.
+func TestModeLearnBPTTWindowTruncatesUnroll(t *testing.T) {
+	m := NewLSTMModel(3, 2, 3, []int{2}, nil, 0.1)
+	vocab := testVocabulary("abc")
+	r := NewCharRNN(m, vocab, 1)
+
+	const window = 2
+	cfg := TrainConfig{SolverKind: SGD, LearnRate: 0.1, BPTTWindow: window}
+	const steps = 5
+	if err := r.ModeLearn(steps, cfg); err != nil {
+		t.Fatalf("ModeLearn: %v", err)
+	}
+
+	if r.window != window {
+		t.Fatalf("r.window = %d, want cfg.BPTTWindow = %d", r.window, window)
+	}
+	if len(r.inputs) != window {
+		t.Fatalf("ModeLearn unrolled %d steps, want %d", len(r.inputs), window)
+	}
+
+	solver, err := NewSolver(cfg)
+	if err != nil {
+		t.Fatalf("NewSolver: %v", err)
+	}
+
+	sentence := []rune("abcabc")
+	retCost, _, err := r.Learn(sentence, 1, solver)
+	if err != nil {
+		t.Fatalf("Learn: %v", err)
+	}
+
+	want := len(sentence) - window
+	if len(retCost) != want {
+		t.Fatalf("Learn ran %d iterations, want %d (len(sentence)-BPTTWindow)", len(retCost), want)
+	}
+}
@@ -0,0 +1,39 @@
+package anomaly
+
+// encoder is satisfied by *lstm.charRNN's Encode method. It is declared
+// locally because charRNN itself is unexported by the lstm package.
+type encoder interface {
+	Encode(sentence []rune) []float32
+}
+
+// LSTMSimilarity computes surprise the same way AverageSimilarity does -
+// average cosine similarity against a ring buffer of past vectors - but
+// over context-aware LSTM encodings of a rune sequence instead of raw
+// bag-of-chars input vectors. It embeds an *AverageSimilarity to reuse
+// its ring buffer bookkeeping rather than duplicating it.
+//
+// LSTMSimilarity satisfies Network and Scorer only through the Train,
+// Observe and Reset methods promoted from the embedded
+// *AverageSimilarity: Train and Observe take an already-encoded
+// []float32 and do not call the encoder. Score is the method that takes
+// a whole rune sequence and encodes it first.
+type LSTMSimilarity struct {
+	encoder encoder
+	*AverageSimilarity
+}
+
+// NewLSTMSimilarity creates a new LSTM-backed similarity surprise
+// engine. enc is typically a *lstm.charRNN built with
+// lstm.NewBidirectionalLSTMModel for richer context.
+func NewLSTMSimilarity(enc encoder) *LSTMSimilarity {
+	return &LSTMSimilarity{
+		encoder:           enc,
+		AverageSimilarity: &AverageSimilarity{vectors: make([][]float32, vectorsSize)},
+	}
+}
+
+// Score computes the surprise of sentence as the average similarity of
+// its LSTM encoding against previously seen encodings.
+func (l *LSTMSimilarity) Score(sentence []rune) float32 {
+	return l.AverageSimilarity.Train(l.encoder.Encode(sentence))
+}
@@ -0,0 +1,40 @@
+package anomaly
+
+import "testing"
+
+type constEncoder struct {
+	vectors [][]float32
+	i       int
+}
+
+func (c *constEncoder) Encode(sentence []rune) []float32 {
+	v := c.vectors[c.i]
+	if c.i < len(c.vectors)-1 {
+		c.i++
+	}
+	return v
+}
+
+// TestLSTMSimilarityScoresEncodedVectors checks that Score encodes the
+// sentence and scores the encoding the way AverageSimilarity.Train
+// does: a repeated encoding scores high average similarity and an
+// orthogonal one scores near zero.
+func TestLSTMSimilarityScoresEncodedVectors(t *testing.T) {
+	repeated := &constEncoder{vectors: [][]float32{{1, 0}, {1, 0}, {1, 0}, {1, 0}}}
+	l := NewLSTMSimilarity(repeated)
+	for i := 0; i < 3; i++ {
+		l.Score([]rune("a"))
+	}
+	if got := l.Score([]rune("a")); got <= 0.9 {
+		t.Fatalf("Score for a repeated encoding = %v, want close to 1", got)
+	}
+
+	orthogonal := &constEncoder{vectors: [][]float32{{1, 0}, {1, 0}, {1, 0}, {0, 1}}}
+	o := NewLSTMSimilarity(orthogonal)
+	for i := 0; i < 3; i++ {
+		o.Score([]rune("a"))
+	}
+	if got := o.Score([]rune("b")); got >= 0.1 {
+		t.Fatalf("Score for an orthogonal encoding = %v, want close to 0", got)
+	}
+}
@@ -0,0 +1,35 @@
+package anomaly
+
+// perplexityModel is satisfied by *lstm.charRNN's Observe/Reset methods.
+// It is declared locally because charRNN itself is unexported by the
+// lstm package.
+type perplexityModel interface {
+	Observe(x []float32) float32
+	Reset()
+}
+
+// PerplexityScorer adapts an LSTM charRNN built with lstm.NewCharRNN and
+// compiled via ModeInference into a Scorer: each Observe call is one
+// step of per-token surprise, -log2(p(observed)), rather than the
+// sentence-level cost Learn optimizes.
+type PerplexityScorer struct {
+	model perplexityModel
+}
+
+// NewPerplexityScorer wraps model, typically a *lstm.charRNN whose
+// ModeInference has already been called. Callers must call Reset before
+// the first Observe to seed the model's start-of-stream state.
+func NewPerplexityScorer(model perplexityModel) *PerplexityScorer {
+	return &PerplexityScorer{model: model}
+}
+
+// Observe feeds x, the one-hot encoding of the next observed token,
+// through the wrapped model and returns its surprise.
+func (p *PerplexityScorer) Observe(x []float32) float32 {
+	return p.model.Observe(x)
+}
+
+// Reset clears the wrapped model's recurrent state.
+func (p *PerplexityScorer) Reset() {
+	p.model.Reset()
+}
@@ -0,0 +1,109 @@
+package anomaly
+
+import (
+	"context"
+	"math"
+)
+
+// Scorer is a streaming anomaly-surprise engine: Observe folds one more
+// input vector into the model's running state and reports how
+// surprising it was, and Reset clears that state to start a fresh
+// stream. AverageSimilarity and PerplexityScorer both satisfy it.
+type Scorer interface {
+	Observe(x []float32) float32
+	Reset()
+}
+
+// AggregateFunc combines the per-scorer surprise scores an
+// EnsembleScorer collects on every Observe call into a single value.
+type AggregateFunc func(scores []float32) float32
+
+// MaxAggregate returns the largest of scores.
+func MaxAggregate(scores []float32) float32 {
+	m := scores[0]
+	for _, s := range scores[1:] {
+		if s > m {
+			m = s
+		}
+	}
+	return m
+}
+
+// MeanAggregate returns the arithmetic mean of scores.
+func MeanAggregate(scores []float32) float32 {
+	var sum float32
+	for _, s := range scores {
+		sum += s
+	}
+	return sum / float32(len(scores))
+}
+
+// LogisticBlend returns an AggregateFunc combining scores with a learned
+// logistic regression, sigmoid(bias + sum(weights[i]*scores[i])). len(weights)
+// must equal the number of scorers in the ensemble it is used with.
+func LogisticBlend(weights []float32, bias float32) AggregateFunc {
+	return func(scores []float32) float32 {
+		z := float64(bias)
+		for i, s := range scores {
+			z += float64(weights[i]) * float64(s)
+		}
+		return float32(1 / (1 + math.Exp(-z)))
+	}
+}
+
+// EnsembleScorer runs several Scorers over the same stream in lock-step
+// and combines their per-step surprise with Aggregate.
+type EnsembleScorer struct {
+	Scorers   []Scorer
+	Aggregate AggregateFunc
+}
+
+// NewEnsembleScorer builds an EnsembleScorer running scorers in
+// lock-step, combined by aggregate (e.g. MaxAggregate, MeanAggregate, or
+// a LogisticBlend).
+func NewEnsembleScorer(aggregate AggregateFunc, scorers ...Scorer) *EnsembleScorer {
+	return &EnsembleScorer{Scorers: scorers, Aggregate: aggregate}
+}
+
+// Observe feeds x to every wrapped scorer and returns their aggregate.
+func (e *EnsembleScorer) Observe(x []float32) float32 {
+	scores := make([]float32, len(e.Scorers))
+	for i, s := range e.Scorers {
+		scores[i] = s.Observe(x)
+	}
+	return e.Aggregate(scores)
+}
+
+// Reset resets every wrapped scorer.
+func (e *EnsembleScorer) Reset() {
+	for _, s := range e.Scorers {
+		s.Reset()
+	}
+}
+
+// StreamScore runs scorer over every vector received on in, emitting its
+// surprise on the returned channel in the same order. The returned
+// channel is closed when in is closed or ctx is done.
+func StreamScore(ctx context.Context, scorer Scorer, in <-chan []float32) <-chan float32 {
+	out := make(chan float32)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case x, ok := <-in:
+				if !ok {
+					return
+				}
+				score := scorer.Observe(x)
+				select {
+				case out <- score:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,59 @@
+package anomaly
+
+import (
+	"context"
+	"testing"
+)
+
+type constScorer struct {
+	score  float32
+	resets int
+}
+
+func (c *constScorer) Observe(x []float32) float32 { return c.score }
+func (c *constScorer) Reset()                      { c.resets++ }
+
+func TestEnsembleScorerAggregatesAndResets(t *testing.T) {
+	a := &constScorer{score: 1}
+	b := &constScorer{score: 3}
+	e := NewEnsembleScorer(MaxAggregate, a, b)
+
+	if got := e.Observe(nil); got != 3 {
+		t.Fatalf("Observe = %v, want 3 (max of 1, 3)", got)
+	}
+
+	e.Reset()
+	if a.resets != 1 || b.resets != 1 {
+		t.Fatalf("Reset did not reach every wrapped scorer: a=%d b=%d", a.resets, b.resets)
+	}
+}
+
+func TestMeanAggregate(t *testing.T) {
+	if got := MeanAggregate([]float32{1, 2, 3}); got != 2 {
+		t.Fatalf("MeanAggregate = %v, want 2", got)
+	}
+}
+
+func TestLogisticBlend(t *testing.T) {
+	blend := LogisticBlend([]float32{0, 0}, 0)
+	if got := blend([]float32{5, -5}); got != 0.5 {
+		t.Fatalf("LogisticBlend with zero weights/bias = %v, want 0.5", got)
+	}
+}
+
+func TestStreamScore(t *testing.T) {
+	in := make(chan []float32, 2)
+	in <- []float32{1}
+	in <- []float32{2}
+	close(in)
+
+	out := StreamScore(context.Background(), &constScorer{score: 7}, in)
+
+	var got []float32
+	for s := range out {
+		got = append(got, s)
+	}
+	if len(got) != 2 || got[0] != 7 || got[1] != 7 {
+		t.Fatalf("StreamScore = %v, want [7 7]", got)
+	}
+}